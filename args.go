@@ -0,0 +1,220 @@
+package fasthttp
+
+import (
+	"bytes"
+)
+
+type argsKV struct {
+	key   []byte
+	value []byte
+}
+
+// Args represents query arguments.
+//
+// It is used in URI.QueryArgs and in POST form argument parsing.
+//
+// Args instance MUST NOT be used from concurrently running goroutines.
+type Args struct {
+	args []argsKV
+	buf  []byte
+}
+
+// Reset clears query args.
+func (a *Args) Reset() {
+	a.args = a.args[:0]
+}
+
+// CopyTo copies all args to dst.
+func (a *Args) CopyTo(dst *Args) {
+	dst.Reset()
+	dst.args = copyArgs(dst.args, a.args)
+}
+
+func copyArgs(dst, src []argsKV) []argsKV {
+	if cap(dst) < len(src) {
+		dst = make([]argsKV, len(src))
+	}
+	dst = dst[:len(src)]
+	for i := range src {
+		dst[i].key = append(dst[i].key[:0], src[i].key...)
+		dst[i].value = append(dst[i].value[:0], src[i].value...)
+	}
+	return dst
+}
+
+// Len returns the number of query args.
+func (a *Args) Len() int {
+	return len(a.args)
+}
+
+// Set sets 'key=value' argument.
+func (a *Args) Set(key, value string) {
+	a.SetBytesKV([]byte(key), []byte(value))
+}
+
+// SetBytesKV sets 'key=value' argument.
+func (a *Args) SetBytesKV(key, value []byte) {
+	for i := range a.args {
+		kv := &a.args[i]
+		if bytes.Equal(kv.key, key) {
+			kv.value = append(kv.value[:0], value...)
+			return
+		}
+	}
+	a.args = append(a.args, argsKV{
+		key:   append([]byte(nil), key...),
+		value: append([]byte(nil), value...),
+	})
+}
+
+// Peek returns query arg value for the given key.
+func (a *Args) Peek(key string) []byte {
+	for i := range a.args {
+		kv := &a.args[i]
+		if string(kv.key) == key {
+			return kv.value
+		}
+	}
+	return nil
+}
+
+// VisitAll calls f for each existing arg in the order of appearance.
+//
+// f must not retain references to key and value after returning.
+func (a *Args) VisitAll(f func(key, value []byte)) {
+	for i := range a.args {
+		kv := &a.args[i]
+		f(kv.key, kv.value)
+	}
+}
+
+// Parse parses the given query string into a.
+func (a *Args) Parse(s string) {
+	a.ParseBytes([]byte(s))
+}
+
+// ParseBytes parses the given query string into a.
+func (a *Args) ParseBytes(b []byte) {
+	a.Reset()
+	for len(b) > 0 {
+		var kv []byte
+		n := bytes.IndexByte(b, '&')
+		if n < 0 {
+			kv = b
+			b = nil
+		} else {
+			kv = b[:n]
+			b = b[n+1:]
+		}
+		if len(kv) == 0 {
+			continue
+		}
+		var key, value []byte
+		n = bytes.IndexByte(kv, '=')
+		if n < 0 {
+			key = kv
+		} else {
+			key = kv[:n]
+			value = kv[n+1:]
+		}
+		a.args = append(a.args, argsKV{
+			key:   decodeArgAppend(nil, key),
+			value: decodeArgAppend(nil, value),
+		})
+	}
+}
+
+// QueryString returns the x-www-form-urlencoded representation of a.
+func (a *Args) QueryString() []byte {
+	a.buf = a.AppendBytes(a.buf[:0])
+	return a.buf
+}
+
+// AppendBytes appends the x-www-form-urlencoded representation of a to dst.
+func (a *Args) AppendBytes(dst []byte) []byte {
+	for i := range a.args {
+		kv := &a.args[i]
+		if i > 0 {
+			dst = append(dst, '&')
+		}
+		dst = appendQuotedArg(dst, kv.key)
+		if len(kv.value) > 0 {
+			dst = append(dst, '=')
+			dst = appendQuotedArg(dst, kv.value)
+		}
+	}
+	return dst
+}
+
+// decodeArgAppend decodes x-www-form-urlencoded src (treating '+' as a
+// space, like query args do) and appends the result to dst.
+func decodeArgAppend(dst, src []byte) []byte {
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch c {
+		case '+':
+			dst = append(dst, ' ')
+		case '%':
+			if i+2 < len(src) {
+				if h, ok := unhex2(src[i+1], src[i+2]); ok {
+					dst = append(dst, h)
+					i += 2
+					continue
+				}
+			}
+			dst = append(dst, c)
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+func unhex2(a, b byte) (byte, bool) {
+	x, ok1 := unhex(a)
+	y, ok2 := unhex(b)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	return x<<4 | y, true
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+const upperhex = "0123456789ABCDEF"
+
+// appendQuotedArg appends the x-www-form-urlencoded representation of src
+// to dst: spaces become '+' and everything outside [A-Za-z0-9-_.] is
+// percent-encoded.
+func appendQuotedArg(dst, src []byte) []byte {
+	for _, c := range src {
+		if isArgSafeByte(c) {
+			dst = append(dst, c)
+		} else if c == ' ' {
+			dst = append(dst, '+')
+		} else {
+			dst = append(dst, '%', upperhex[c>>4], upperhex[c&0xf])
+		}
+	}
+	return dst
+}
+
+func isArgSafeByte(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	case c == '-' || c == '_' || c == '.':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package fasthttp
+
+// CookieJar manages storage and use of cookies across fasthttp.Client
+// requests, analogous to net/http.CookieJar.
+//
+// A Client is meant to consult a Jar field (if set) before sending a
+// request, to inject any matching stored cookies into the Cookie header,
+// and after receiving a response, to persist any cookies set via
+// Set-Cookie - the same as net/http.Client does with its own Jar. That
+// wiring does not exist yet: nothing in this package currently calls
+// SetCookies/Cookies. cookiejar.Jar below is a complete, independently
+// usable CookieJar implementation, but it is not yet consulted by
+// anything that sends requests.
+//
+// Implementations must be safe for concurrent use by multiple goroutines.
+type CookieJar interface {
+	// SetCookies handles the receipt of cookies in a response to a request
+	// to uri, storing the ones that should be retained for future requests.
+	SetCookies(uri *URI, cookies []*Cookie)
+
+	// Cookies returns the cookies that should be sent in a request to uri.
+	// Callers own the returned slice and must release each Cookie with
+	// ReleaseCookie once done with it.
+	Cookies(uri *URI) []*Cookie
+}
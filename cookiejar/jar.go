@@ -0,0 +1,273 @@
+// Package cookiejar implements an in-memory fasthttp.CookieJar, the
+// counterpart of net/http/cookiejar for fasthttp.Client.
+//
+// Jar is a complete, standalone CookieJar: nothing in fasthttp itself
+// consults one yet (see the Jar field note on fasthttp.CookieJar), so a
+// caller wanting cookie persistence today must call SetCookies/Cookies
+// around its own Client.Do calls directly.
+package cookiejar
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vickysongang/fasthttp"
+)
+
+// PublicSuffixList provides the public suffix of a domain. For example:
+//   - the public suffix of "example.com" is "com",
+//   - the public suffix of "foo1.foo2.foo3.co.uk" is "co.uk", and
+//   - the public suffix of "bar.pvt.k12.ma.us" is "pvt.k12.ma.us".
+//
+// A nil PublicSuffixList is equivalent to one that always returns "",
+// i.e. it has no public suffix rules and every domain is considered
+// eligible to hold cookies for its bare form.
+type PublicSuffixList interface {
+	// PublicSuffix returns the public suffix of domain.
+	PublicSuffix(domain string) string
+
+	// String returns a description of the source of this public suffix
+	// list, e.g. "publicsuffix.org's public_suffix_list.dat checked in at
+	// 2018-07-24".
+	String() string
+}
+
+// Options holds the parameters for New.
+type Options struct {
+	// PublicSuffixList, if non-nil, is used to reject cookies whose Domain
+	// attribute is a public suffix, the same way net/http/cookiejar does.
+	PublicSuffixList PublicSuffixList
+}
+
+// Jar implements fasthttp.CookieJar, storing cookies in memory keyed by
+// the canonical form of their host.
+type Jar struct {
+	psList PublicSuffixList
+
+	mu      sync.Mutex
+	entries map[string]map[string]entry
+}
+
+// entry is the internal representation of a cookie.
+//
+// Unlike fasthttp.Cookie, it contains extra fields - the effective Domain,
+// HostOnly and Expires - that are derived from the wire representation of
+// a cookie.
+type entry struct {
+	Name       string
+	Value      string
+	Domain     string
+	Path       string
+	Secure     bool
+	HttpOnly   bool
+	HostOnly   bool
+	Expires    time.Time
+	Creation   time.Time
+	LastAccess time.Time
+}
+
+// New returns a new cookie jar. A nil *Options is equivalent to a zero
+// Options.
+func New(o *Options) (*Jar, error) {
+	jar := &Jar{
+		entries: make(map[string]map[string]entry),
+	}
+	if o != nil {
+		jar.psList = o.PublicSuffixList
+	}
+	return jar, nil
+}
+
+// SetCookies implements fasthttp.CookieJar, storing cookies received in a
+// response from uri's host into the jar.
+func (j *Jar) SetCookies(uri *fasthttp.URI, cookies []*fasthttp.Cookie) {
+	host, err := canonicalHost(string(uri.Host()))
+	if err != nil {
+		return
+	}
+	path := string(uri.Path())
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	for _, c := range cookies {
+		e, ok := j.newEntry(c, now, host, path)
+		if !ok {
+			continue
+		}
+		submap := j.entries[e.Domain]
+		if submap == nil {
+			submap = make(map[string]entry)
+		}
+		id := e.Name + ";" + e.Path
+		if e.Expires.Before(now) {
+			delete(submap, id)
+		} else {
+			submap[id] = e
+		}
+		if len(submap) == 0 {
+			delete(j.entries, e.Domain)
+		} else {
+			j.entries[e.Domain] = submap
+		}
+	}
+}
+
+// newEntry builds an entry from a Set-Cookie header, validating its Domain
+// attribute against the public suffix list. path is the path of the
+// request that produced the Set-Cookie header, used as the basis for the
+// cookie's default-path when it carries no explicit Path attribute.
+func (j *Jar) newEntry(c *fasthttp.Cookie, now time.Time, host, path string) (entry, bool) {
+	e := entry{
+		Name:       string(c.Key()),
+		Value:      string(c.Value()),
+		Path:       string(c.Path()),
+		Secure:     c.Secure(),
+		HttpOnly:   c.HTTPOnly(),
+		Creation:   now,
+		LastAccess: now,
+	}
+	if e.Name == "" {
+		return entry{}, false
+	}
+
+	domain := string(c.Domain())
+	if domain == "" {
+		e.Domain = host
+		e.HostOnly = true
+	} else {
+		domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+		if !j.isValidCookieDomain(domain, host) {
+			return entry{}, false
+		}
+		e.Domain = domain
+		e.HostOnly = false
+	}
+
+	if e.Path == "" || e.Path[0] != '/' {
+		e.Path = defaultPath(path)
+	}
+
+	if exp := c.Expire(); !exp.IsZero() && exp != fasthttp.CookieExpireUnlimited {
+		e.Expires = exp
+	} else {
+		// Session cookie: keep it around for the lifetime of the jar.
+		e.Expires = now.Add(100 * 365 * 24 * time.Hour)
+	}
+
+	return e, true
+}
+
+// isValidCookieDomain reports whether domain is an acceptable Domain
+// attribute for a cookie set while visiting host: it must be host itself,
+// or a parent of host that is not a public suffix.
+func (j *Jar) isValidCookieDomain(domain, host string) bool {
+	if domain == host {
+		return true
+	}
+	if !strings.HasSuffix(host, "."+domain) {
+		return false
+	}
+	if j.psList == nil {
+		return true
+	}
+	if ps := j.psList.PublicSuffix(domain); ps == domain {
+		return false
+	}
+	return true
+}
+
+// defaultPath computes the default-path for a cookie set without an
+// explicit Path attribute, per RFC 6265 section 5.1.4: the directory of
+// requestPath (i.e. requestPath with its last "/"-separated segment
+// dropped), or "/" if requestPath has no parent directory.
+func defaultPath(requestPath string) string {
+	if requestPath == "" || requestPath[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndexByte(requestPath, '/')
+	if i == 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+// Cookies implements fasthttp.CookieJar, returning the cookies that should
+// be sent in a request to uri.
+func (j *Jar) Cookies(uri *fasthttp.URI) []*fasthttp.Cookie {
+	host, err := canonicalHost(string(uri.Host()))
+	if err != nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	path := string(uri.Path())
+	secure := strings.EqualFold(string(uri.Scheme()), "https")
+
+	var out []*fasthttp.Cookie
+	for domain, submap := range j.entries {
+		if !domainMatches(domain, host) {
+			continue
+		}
+		for id, e := range submap {
+			if e.HostOnly && e.Domain != host {
+				continue
+			}
+			if !pathMatches(e.Path, path) {
+				continue
+			}
+			if e.Secure && !secure {
+				continue
+			}
+			if e.Expires.Before(now) {
+				delete(submap, id)
+				continue
+			}
+			c := fasthttp.AcquireCookie()
+			c.SetKey(e.Name)
+			c.SetValue(e.Value)
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func domainMatches(cookieDomain, host string) bool {
+	return cookieDomain == host || strings.HasSuffix(host, "."+cookieDomain)
+}
+
+func pathMatches(cookiePath, requestPath string) bool {
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	if cookiePath == requestPath {
+		return true
+	}
+	if strings.HasPrefix(requestPath, cookiePath) {
+		if cookiePath[len(cookiePath)-1] == '/' {
+			return true
+		}
+		if requestPath[len(cookiePath)] == '/' {
+			return true
+		}
+	}
+	return false
+}
+
+func canonicalHost(host string) (string, error) {
+	host = strings.ToLower(host)
+	if host == "" {
+		return "", errors.New("cookiejar: empty host")
+	}
+	if idx := strings.IndexByte(host, ':'); idx != -1 {
+		host = host[:idx]
+	}
+	host = strings.TrimSuffix(host, ".")
+	return host, nil
+}
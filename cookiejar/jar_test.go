@@ -0,0 +1,90 @@
+package cookiejar
+
+import "testing"
+
+func TestDefaultPath(t *testing.T) {
+	testCases := []struct {
+		requestPath string
+		expected    string
+	}{
+		{"/dir/file", "/dir"},
+		{"/file", "/"},
+		{"/", "/"},
+		{"", "/"},
+		{"noslash", "/"},
+		{"/a/b/c", "/a/b"},
+	}
+	for _, tc := range testCases {
+		if got := defaultPath(tc.requestPath); got != tc.expected {
+			t.Fatalf("defaultPath(%q) = %q. Expecting %q", tc.requestPath, got, tc.expected)
+		}
+	}
+}
+
+func TestPathMatches(t *testing.T) {
+	testCases := []struct {
+		cookiePath  string
+		requestPath string
+		expected    bool
+	}{
+		{"/", "/foo", true},
+		{"/foo", "/foo", true},
+		{"/foo", "/foo/bar", true},
+		{"/foo/", "/foo/bar", true},
+		{"/foo", "/foobar", false},
+		{"/foo", "/bar", false},
+		{"/foo", "", false},
+	}
+	for _, tc := range testCases {
+		if got := pathMatches(tc.cookiePath, tc.requestPath); got != tc.expected {
+			t.Fatalf("pathMatches(%q, %q) = %v. Expecting %v", tc.cookiePath, tc.requestPath, got, tc.expected)
+		}
+	}
+}
+
+// fakePublicSuffixList treats every domain in suffixes as a public suffix,
+// for exercising isValidCookieDomain without the real publicsuffix data.
+type fakePublicSuffixList struct {
+	suffixes map[string]bool
+}
+
+func (l *fakePublicSuffixList) PublicSuffix(domain string) string {
+	if l.suffixes[domain] {
+		return domain
+	}
+	return ""
+}
+
+func (l *fakePublicSuffixList) String() string {
+	return "fakePublicSuffixList"
+}
+
+func TestIsValidCookieDomain(t *testing.T) {
+	testCases := []struct {
+		name     string
+		psList   PublicSuffixList
+		domain   string
+		host     string
+		expected bool
+	}{
+		{"exact match, no psList", nil, "example.com", "example.com", true},
+		{"parent domain, no psList", nil, "example.com", "www.example.com", true},
+		{"unrelated domain, no psList", nil, "example.com", "other.com", false},
+		{
+			"parent domain is a public suffix",
+			&fakePublicSuffixList{suffixes: map[string]bool{"co.uk": true}},
+			"co.uk", "www.co.uk", false,
+		},
+		{
+			"parent domain is not a public suffix",
+			&fakePublicSuffixList{suffixes: map[string]bool{"co.uk": true}},
+			"example.co.uk", "www.example.co.uk", true,
+		},
+	}
+	for _, tc := range testCases {
+		j := &Jar{psList: tc.psList}
+		if got := j.isValidCookieDomain(tc.domain, tc.host); got != tc.expected {
+			t.Fatalf("%s: isValidCookieDomain(%q, %q) = %v. Expecting %v", tc.name, tc.domain, tc.host, got, tc.expected)
+		}
+	}
+}
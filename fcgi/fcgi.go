@@ -0,0 +1,476 @@
+// Package fcgi implements the FastCGI responder role on top of fasthttp,
+// so a fasthttp.RequestHandler can be run behind an nginx/Apache front end
+// instead of (or in addition to) listening on a plain TCP/Unix socket.
+//
+// Only the responder role is implemented: filter and authorizer requests
+// are rejected with an FCGI_END_REQUEST record whose protocol status is
+// FCGI_UNKNOWN_ROLE, matching net/http/fcgi's behavior.
+package fcgi
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/vickysongang/fasthttp"
+)
+
+// FastCGI record types, as defined by the FastCGI spec.
+const (
+	typeBeginRequest    = 1
+	typeAbortRequest    = 2
+	typeEndRequest      = 3
+	typeParams          = 4
+	typeStdin           = 5
+	typeStdout          = 6
+	typeStderr          = 7
+	typeData            = 8
+	typeGetValues       = 9
+	typeGetValuesResult = 10
+	typeUnknownType     = 11
+)
+
+// Flags for FCGI_BEGIN_REQUEST.
+const (
+	flagKeepConn = 1
+)
+
+// Roles for FCGI_BEGIN_REQUEST.
+const (
+	roleResponder  = 1
+	roleAuthorizer = 2
+	roleFilter     = 3
+)
+
+// Protocol statuses for FCGI_END_REQUEST.
+const (
+	statusRequestComplete = 0
+	statusCantMultiplex   = 1
+	statusOverloaded      = 2
+	statusUnknownRole     = 3
+)
+
+const maxWrite = 65535 - 8 // maximum content written per record, 8 below the uint16 length limit
+
+// Serve accepts connections on l and speaks the FastCGI responder protocol
+// on each of them, dispatching requests to handler.
+//
+// Serve blocks until l is closed or returns an error, which is then
+// returned to the caller. It is typically run in its own goroutine, the
+// same way fasthttp.Serve is.
+func Serve(l net.Listener, handler fasthttp.RequestHandler) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		c := &child{
+			conn:    conn,
+			handler: handler,
+			reqs:    make(map[uint16]*request),
+		}
+		go c.serve()
+	}
+}
+
+// header is the 8-byte FastCGI record header.
+type header struct {
+	Version       uint8
+	Type          uint8
+	RequestID     uint16
+	ContentLength uint16
+	PaddingLength uint8
+	Reserved      uint8
+}
+
+func readHeader(r io.Reader) (header, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return header{}, err
+	}
+	return header{
+		Version:       buf[0],
+		Type:          buf[1],
+		RequestID:     binary.BigEndian.Uint16(buf[2:4]),
+		ContentLength: binary.BigEndian.Uint16(buf[4:6]),
+		PaddingLength: buf[6],
+	}, nil
+}
+
+// writeRecord splits content into maxWrite-sized chunks and writes one
+// record per chunk. A nil/empty content is still written once - as a
+// single zero-length record - since that's how FCGI_STDOUT signals
+// end-of-stream (and any other record type with no content is legitimately
+// a single empty record, not zero records).
+func writeRecord(w io.Writer, recType uint8, reqID uint16, content []byte) error {
+	for first := true; first || len(content) > 0; first = false {
+		n := len(content)
+		if n > maxWrite {
+			n = maxWrite
+		}
+		if err := writeRecordHeader(w, recType, reqID, content[:n]); err != nil {
+			return err
+		}
+		content = content[n:]
+	}
+	return nil
+}
+
+func writeRecordHeader(w io.Writer, recType uint8, reqID uint16, content []byte) error {
+	padding := (8 - len(content)%8) % 8
+	var buf [8]byte
+	buf[0] = 1
+	buf[1] = recType
+	binary.BigEndian.PutUint16(buf[2:4], reqID)
+	binary.BigEndian.PutUint16(buf[4:6], uint16(len(content)))
+	buf[6] = uint8(padding)
+	if _, err := w.Write(buf[:]); err != nil {
+		return err
+	}
+	if len(content) > 0 {
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+	}
+	if padding > 0 {
+		var zero [8]byte
+		if _, err := w.Write(zero[:padding]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// request holds the state accumulated for a single FCGI request id while
+// its records are streamed in.
+type request struct {
+	params   []byte
+	stdin    []byte
+	keepConn bool
+}
+
+// child handles a single FastCGI connection, which may multiplex several
+// concurrent requests. Each request is dispatched to its own goroutine as
+// soon as its STDIN stream closes, so a slow handler for one request can't
+// stall the PARAMS/STDIN of another multiplexed on the same connection;
+// writeMu serializes the STDOUT/END_REQUEST records those goroutines (and
+// the read loop itself) write back.
+type child struct {
+	conn    net.Conn
+	handler fasthttp.RequestHandler
+
+	writeMu sync.Mutex
+
+	mu   sync.Mutex
+	reqs map[uint16]*request
+
+	wg sync.WaitGroup
+}
+
+func (c *child) serve() {
+	defer c.conn.Close()
+	defer c.wg.Wait()
+	br := bufio.NewReader(c.conn)
+
+	for {
+		h, err := readHeader(br)
+		if err != nil {
+			return
+		}
+		content := make([]byte, h.ContentLength)
+		if _, err := io.ReadFull(br, content); err != nil {
+			return
+		}
+		if h.PaddingLength > 0 {
+			if _, err := io.CopyN(ioutil.Discard, br, int64(h.PaddingLength)); err != nil {
+				return
+			}
+		}
+
+		if err := c.handleRecord(h, content); err != nil {
+			return
+		}
+	}
+}
+
+func (c *child) writeRecord(recType uint8, reqID uint16, content []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return writeRecord(c.conn, recType, reqID, content)
+}
+
+func (c *child) handleRecord(h header, content []byte) error {
+	switch h.Type {
+	case typeBeginRequest:
+		if len(content) < 8 {
+			return nil
+		}
+		role := binary.BigEndian.Uint16(content[0:2])
+		flags := content[2]
+		if role != roleResponder {
+			return c.writeRecord(typeEndRequest, h.RequestID, endRequestBody(0, statusUnknownRole))
+		}
+		c.mu.Lock()
+		c.reqs[h.RequestID] = &request{keepConn: flags&flagKeepConn != 0}
+		c.mu.Unlock()
+
+	case typeParams:
+		c.mu.Lock()
+		req := c.reqs[h.RequestID]
+		c.mu.Unlock()
+		if req == nil {
+			return nil
+		}
+		req.params = append(req.params, content...)
+
+	case typeStdin:
+		c.mu.Lock()
+		req := c.reqs[h.RequestID]
+		c.mu.Unlock()
+		if req == nil {
+			return nil
+		}
+		if len(content) == 0 {
+			// stdin stream closed - we have the full request now. Run the
+			// handler on its own goroutine so other requests multiplexed on
+			// this connection keep streaming in while this one executes.
+			c.mu.Lock()
+			delete(c.reqs, h.RequestID)
+			c.mu.Unlock()
+			c.wg.Add(1)
+			go func(reqID uint16, req *request) {
+				defer c.wg.Done()
+				if err := c.respond(reqID, req); err != nil || !req.keepConn {
+					c.conn.Close()
+				}
+			}(h.RequestID, req)
+			return nil
+		}
+		req.stdin = append(req.stdin, content...)
+
+	case typeAbortRequest:
+		c.mu.Lock()
+		delete(c.reqs, h.RequestID)
+		c.mu.Unlock()
+		if err := c.writeRecord(typeEndRequest, h.RequestID, endRequestBody(0, statusRequestComplete)); err != nil {
+			return err
+		}
+
+	case typeGetValues:
+		return c.handleGetValues(content)
+
+	default:
+		return c.writeRecord(typeUnknownType, h.RequestID, []byte{h.Type, 0, 0, 0, 0, 0, 0, 0})
+	}
+	return nil
+}
+
+func (c *child) handleGetValues(content []byte) error {
+	// Only FCGI_MAX_CONNS / FCGI_MAX_REQS / FCGI_MPXS_CONNS are ever queried
+	// in practice; report conservative single-request values.
+	values := map[string]string{
+		"FCGI_MAX_CONNS":  "1",
+		"FCGI_MAX_REQS":   "1",
+		"FCGI_MPXS_CONNS": "0",
+	}
+	var out []byte
+	for name := range decodePairNames(content) {
+		if v, ok := values[name]; ok {
+			out = appendNameValuePair(out, name, v)
+		}
+	}
+	return c.writeRecord(typeGetValuesResult, 0, out)
+}
+
+func decodePairNames(content []byte) map[string]struct{} {
+	names := make(map[string]struct{})
+	for len(content) > 0 {
+		nameLen, n := readSize(content)
+		content = content[n:]
+		_, n = readSize(content)
+		content = content[n:]
+		if len(content) < nameLen {
+			break
+		}
+		names[string(content[:nameLen])] = struct{}{}
+		content = content[nameLen:]
+	}
+	return names
+}
+
+func endRequestBody(appStatus uint32, protocolStatus uint8) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint32(buf[0:4], appStatus)
+	buf[4] = protocolStatus
+	return buf[:]
+}
+
+func readSize(b []byte) (int, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	if b[0]>>7 == 0 {
+		return int(b[0]), 1
+	}
+	if len(b) < 4 {
+		return 0, 0
+	}
+	return int(binary.BigEndian.Uint32(b[0:4]) & 0x7fffffff), 4
+}
+
+func appendSize(dst []byte, size int) []byte {
+	if size <= 127 {
+		return append(dst, byte(size))
+	}
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(size)|1<<31)
+	return append(dst, buf[:]...)
+}
+
+func appendNameValuePair(dst []byte, name, value string) []byte {
+	dst = appendSize(dst, len(name))
+	dst = appendSize(dst, len(value))
+	dst = append(dst, name...)
+	dst = append(dst, value...)
+	return dst
+}
+
+// requestCtxPool and requestPool let concurrent respond calls on the same
+// (or different) connections reuse their fasthttp.Request/RequestCtx
+// instead of allocating one per FCGI request, so the fast-path benchmarks
+// still hold under FCGI.
+var requestCtxPool = sync.Pool{
+	New: func() interface{} { return &fasthttp.RequestCtx{} },
+}
+
+var requestPool = sync.Pool{
+	New: func() interface{} { return &fasthttp.Request{} },
+}
+
+// respond decodes the accumulated PARAMS/STDIN of req, runs the handler and
+// streams the fasthttp response back as FCGI_STDOUT records.
+func (c *child) respond(reqID uint16, req *request) error {
+	r := requestPool.Get().(*fasthttp.Request)
+	r.Reset()
+	defer requestPool.Put(r)
+
+	decodeParamsInto(req.params, r)
+	if len(req.stdin) > 0 {
+		r.SetBody(req.stdin)
+	}
+
+	ctx := requestCtxPool.Get().(*fasthttp.RequestCtx)
+	defer requestCtxPool.Put(ctx)
+	ctx.Init(r, c.conn.RemoteAddr(), nil)
+	c.handler(ctx)
+
+	var respBuf []byte
+	respBuf = append(respBuf, "Status: "...)
+	respBuf = append(respBuf, statusLine(ctx.Response.StatusCode())...)
+	respBuf = append(respBuf, "\r\n"...)
+	ctx.Response.Header.VisitAll(func(k, v []byte) {
+		respBuf = append(respBuf, k...)
+		respBuf = append(respBuf, ": "...)
+		respBuf = append(respBuf, v...)
+		respBuf = append(respBuf, "\r\n"...)
+	})
+	respBuf = append(respBuf, "\r\n"...)
+	respBuf = append(respBuf, ctx.Response.Body()...)
+
+	if err := c.writeRecord(typeStdout, reqID, respBuf); err != nil {
+		return err
+	}
+	if err := c.writeRecord(typeStdout, reqID, nil); err != nil {
+		return err
+	}
+	return c.writeRecord(typeEndRequest, reqID, endRequestBody(0, statusRequestComplete))
+}
+
+// decodeParamsInto walks the name-value pairs accumulated from FCGI_PARAMS
+// and sets each recognized one directly on r's byte-slice header/URI
+// fields, without ever materializing a map[string]string: every CGI param
+// is decoded and consumed exactly once, preserving the zero-alloc parsing
+// fasthttp.Request otherwise gets when reading straight off the wire.
+//
+// REQUEST_METHOD, REQUEST_URI, HTTP_HOST, CONTENT_TYPE, CONTENT_LENGTH and
+// QUERY_STRING are mapped onto their fasthttp equivalents; HTTP_* params
+// become the corresponding request header. SERVER_PROTOCOL is not mapped:
+// fasthttp requests have no separate protocol-version field to set (the
+// server side only ever speaks HTTP/1.1), so there is nothing for it to
+// feed into here.
+func decodeParamsInto(content []byte, r *fasthttp.Request) {
+	for len(content) > 0 {
+		nameLen, n := readSize(content)
+		content = content[n:]
+		valueLen, n := readSize(content)
+		content = content[n:]
+		if len(content) < nameLen+valueLen {
+			return
+		}
+		name := content[:nameLen]
+		value := content[nameLen : nameLen+valueLen]
+		content = content[nameLen+valueLen:]
+
+		switch {
+		case bytesEqualStr(name, "REQUEST_METHOD"):
+			r.Header.SetMethodBytes(value)
+		case bytesEqualStr(name, "REQUEST_URI"):
+			r.Header.SetRequestURIBytes(value)
+		case bytesEqualStr(name, "HTTP_HOST"):
+			r.Header.SetHostBytes(value)
+		case bytesEqualStr(name, "CONTENT_TYPE"):
+			if len(value) > 0 {
+				r.Header.SetContentTypeBytes(value)
+			}
+		case bytesEqualStr(name, "CONTENT_LENGTH"):
+			if n, err := strconv.Atoi(string(value)); err == nil {
+				r.Header.SetContentLength(n)
+			}
+		case bytesEqualStr(name, "QUERY_STRING"):
+			if len(value) > 0 {
+				r.URI().SetQueryString(string(value))
+			}
+		case len(name) > len(httpParamPrefix) && bytesEqualStr(name[:len(httpParamPrefix)], httpParamPrefix):
+			r.Header.SetBytesKV(headerNameFromParam(name[len(httpParamPrefix):]), value)
+		}
+	}
+}
+
+const httpParamPrefix = "HTTP_"
+
+// bytesEqualStr reports whether b, as a string, equals s - written this
+// way (rather than string(b) == s at the call site) purely for readability;
+// the compiler still recognizes the pattern and avoids copying b.
+func bytesEqualStr(b []byte, s string) bool {
+	return string(b) == s
+}
+
+// headerNameFromParam turns "FOO_BAR" (as found after the HTTP_ prefix of
+// a CGI param) into the canonical "Foo-Bar" header form, in place: name is
+// a sub-slice of the request's own params buffer, which is discarded once
+// respond returns, so mutating it here costs nothing extra.
+func headerNameFromParam(name []byte) []byte {
+	upper := true
+	for i, c := range name {
+		switch {
+		case c == '_':
+			name[i] = '-'
+			upper = true
+		case upper:
+			upper = false
+		default:
+			if c >= 'A' && c <= 'Z' {
+				name[i] = c - 'A' + 'a'
+			}
+		}
+	}
+	return name
+}
+
+func statusLine(code int) string {
+	return strconv.Itoa(code) + " " + fasthttp.StatusMessage(code)
+}
@@ -0,0 +1,248 @@
+package fcgi
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vickysongang/fasthttp"
+)
+
+// TestWriteRecordEmptyContent is a regression test for an infinite loop:
+// writeRecord used to never terminate when called with nil content (the way
+// respond signals FCGI_STDOUT end-of-stream), because reslicing nil content
+// by a zero-length chunk kept the loop's "more to write" condition true
+// forever. A nil/empty content must still produce exactly one zero-length
+// record.
+func TestWriteRecordEmptyContent(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeRecord(&buf, typeStdout, 1, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if buf.Len() != 8 {
+		t.Fatalf("unexpected record length %d. Expecting a single 8-byte header, got %q", buf.Len(), buf.Bytes())
+	}
+}
+
+// TestWriteRecordEmptyContentTerminates guards the same regression as
+// TestWriteRecordEmptyContent, but from the other side: if the infinite
+// loop ever came back, writeRecord would spin forever instead of failing,
+// hanging the whole test binary rather than just this test. Running it on
+// its own goroutine with a timeout turns that hang into a normal failure.
+func TestWriteRecordEmptyContentTerminates(t *testing.T) {
+	done := make(chan error, 1)
+	go func() {
+		var buf bytes.Buffer
+		done <- writeRecord(&buf, typeStdout, 1, nil)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("writeRecord(nil) did not terminate within 1s")
+	}
+}
+
+func TestWriteRecordSplitsLargeContent(t *testing.T) {
+	content := make([]byte, maxWrite+100)
+	for i := range content {
+		content[i] = byte(i)
+	}
+
+	var buf bytes.Buffer
+	if err := writeRecord(&buf, typeStdout, 1, content); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	r := buf.Bytes()
+	h, err := readHeader(bytes.NewReader(r))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if int(h.ContentLength) != maxWrite {
+		t.Fatalf("unexpected first record content length %d. Expecting %d", h.ContentLength, maxWrite)
+	}
+}
+
+func TestDecodeParamsInto(t *testing.T) {
+	var content []byte
+	content = appendNameValuePair(content, "REQUEST_METHOD", "POST")
+	content = appendNameValuePair(content, "REQUEST_URI", "/foo/bar?baz=1")
+	content = appendNameValuePair(content, "HTTP_HOST", "example.com")
+	content = appendNameValuePair(content, "CONTENT_TYPE", "application/json")
+	content = appendNameValuePair(content, "CONTENT_LENGTH", "42")
+	content = appendNameValuePair(content, "QUERY_STRING", "baz=1")
+	content = appendNameValuePair(content, "HTTP_X_CUSTOM_HEADER", "custom-value")
+	content = appendNameValuePair(content, "SERVER_PROTOCOL", "HTTP/1.1")
+
+	var r fasthttp.Request
+	decodeParamsInto(content, &r)
+
+	if string(r.Header.Method()) != "POST" {
+		t.Fatalf("unexpected method %q", r.Header.Method())
+	}
+	if string(r.Header.Host()) != "example.com" {
+		t.Fatalf("unexpected host %q", r.Header.Host())
+	}
+	if string(r.Header.ContentType()) != "application/json" {
+		t.Fatalf("unexpected content type %q", r.Header.ContentType())
+	}
+	if r.Header.ContentLength() != 42 {
+		t.Fatalf("unexpected content length %d", r.Header.ContentLength())
+	}
+	if string(r.URI().QueryString()) != "baz=1" {
+		t.Fatalf("unexpected query string %q", r.URI().QueryString())
+	}
+	if v := r.Header.Peek("X-Custom-Header"); string(v) != "custom-value" {
+		t.Fatalf("unexpected X-Custom-Header %q", v)
+	}
+}
+
+func TestHeaderNameFromParam(t *testing.T) {
+	testCases := []struct {
+		param    string
+		expected string
+	}{
+		{"X_CUSTOM_HEADER", "X-Custom-Header"},
+		{"ACCEPT", "Accept"},
+		{"USER_AGENT", "User-Agent"},
+	}
+	for _, tc := range testCases {
+		got := string(headerNameFromParam([]byte(tc.param)))
+		if got != tc.expected {
+			t.Fatalf("headerNameFromParam(%q) = %q. Expecting %q", tc.param, got, tc.expected)
+		}
+	}
+}
+
+func fcgiEchoHandler(ctx *fasthttp.RequestCtx) {
+	ctx.Success("text/plain", ctx.RequestURI())
+}
+
+// TestServeConcurrentKeepConn drives two multiplexed FCGI requests over a
+// single FCGI_KEEP_CONN connection and checks that both come back with the
+// right body for their own request id - i.e. that dispatching each request
+// on its own goroutine doesn't let one clobber another - and that the
+// connection is still usable for a request afterwards instead of being
+// closed.
+func TestServeConcurrentKeepConn(t *testing.T) {
+	addr := "127.0.0.1:8987"
+	ln, err := net.Listen("tcp4", addr)
+	if err != nil {
+		t.Fatalf("cannot listen %q: %s", addr, err)
+	}
+
+	ch := make(chan struct{})
+	go func() {
+		if err := Serve(ln, fcgiEchoHandler); err != nil && !strings.Contains(
+			err.Error(), "use of closed network connection") {
+			t.Errorf("error when serving requests: %s", err)
+		}
+		close(ch)
+	}()
+
+	conn, err := net.Dial("tcp4", addr)
+	if err != nil {
+		t.Fatalf("cannot dial %q: %s", addr, err)
+	}
+
+	sendRequest(t, conn, 1, "/req/one", true)
+	sendRequest(t, conn, 2, "/req/two", true)
+
+	bodies := readResponses(t, conn, 1, 2)
+	if !strings.Contains(bodies[1], "/req/one") {
+		t.Fatalf("unexpected body for request 1: %q", bodies[1])
+	}
+	if !strings.Contains(bodies[2], "/req/two") {
+		t.Fatalf("unexpected body for request 2: %q", bodies[2])
+	}
+
+	sendRequest(t, conn, 3, "/req/three", false)
+	if bodies := readResponses(t, conn, 3); !strings.Contains(bodies[3], "/req/three") {
+		t.Fatalf("unexpected body for request 3 on reused connection: %q", bodies[3])
+	}
+
+	conn.Close()
+	ln.Close()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatalf("server wasn't stopped")
+	}
+}
+
+func sendRequest(t *testing.T, conn net.Conn, reqID uint16, requestURI string, keepConn bool) {
+	t.Helper()
+
+	var flags byte
+	if keepConn {
+		flags = flagKeepConn
+	}
+	beginBody := []byte{0, roleResponder, flags, 0, 0, 0, 0, 0}
+	if err := writeRecord(conn, typeBeginRequest, reqID, beginBody); err != nil {
+		t.Fatalf("cannot write begin request: %s", err)
+	}
+
+	var params []byte
+	params = appendNameValuePair(params, "REQUEST_METHOD", "GET")
+	params = appendNameValuePair(params, "REQUEST_URI", requestURI)
+	if err := writeRecord(conn, typeParams, reqID, params); err != nil {
+		t.Fatalf("cannot write params: %s", err)
+	}
+	if err := writeRecord(conn, typeParams, reqID, nil); err != nil {
+		t.Fatalf("cannot write params terminator: %s", err)
+	}
+	if err := writeRecord(conn, typeStdin, reqID, nil); err != nil {
+		t.Fatalf("cannot write stdin terminator: %s", err)
+	}
+}
+
+// readResponses reads records off conn, demultiplexing FCGI_STDOUT content
+// by request id, until every id in want has produced an FCGI_END_REQUEST -
+// exercising the fact that multiplexed requests may have their stdout
+// records interleaved on the wire.
+func readResponses(t *testing.T, conn net.Conn, want ...uint16) map[uint16]string {
+	t.Helper()
+
+	bodies := map[uint16][]byte{}
+	pending := map[uint16]bool{}
+	for _, id := range want {
+		pending[id] = true
+	}
+
+	for len(pending) > 0 {
+		h, err := readHeader(conn)
+		if err != nil {
+			t.Fatalf("cannot read header: %s", err)
+		}
+		content := make([]byte, h.ContentLength)
+		if _, err := io.ReadFull(conn, content); err != nil {
+			t.Fatalf("cannot read content: %s", err)
+		}
+		if h.PaddingLength > 0 {
+			if _, err := io.CopyN(ioutil.Discard, conn, int64(h.PaddingLength)); err != nil {
+				t.Fatalf("cannot discard padding: %s", err)
+			}
+		}
+
+		switch h.Type {
+		case typeStdout:
+			bodies[h.RequestID] = append(bodies[h.RequestID], content...)
+		case typeEndRequest:
+			delete(pending, h.RequestID)
+		}
+	}
+
+	out := make(map[uint16]string, len(bodies))
+	for id, b := range bodies {
+		out[id] = string(b)
+	}
+	return out
+}
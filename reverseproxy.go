@@ -0,0 +1,174 @@
+package fasthttp
+
+import (
+	"log"
+	"net"
+	"strings"
+)
+
+// hopHeaders are headers that apply only to a single transport-level
+// connection and must not be forwarded by a proxy, as specified by
+// RFC 7230, section 6.1.
+var hopHeaders = [][]byte{
+	strConnection,
+	[]byte("Keep-Alive"),
+	[]byte("Proxy-Authenticate"),
+	[]byte("Proxy-Authorization"),
+	strTransferEncoding,
+	strUpgrade,
+	[]byte("Te"),
+	[]byte("Trailer"),
+}
+
+// ReverseProxy is a RequestHandler that forwards incoming requests to a
+// backend server using a fasthttp.Client and writes the backend's response
+// back to the client, analogous to net/http/httputil.ReverseProxy.
+type ReverseProxy struct {
+	// Director rewrites the outgoing request, e.g. to set req.SetHost and
+	// req.SetRequestURI to point at the backend. It is called after the
+	// hop-by-hop headers have been stripped from req and the
+	// X-Forwarded-* headers set on it. Director must be set.
+	Director func(req *Request)
+
+	// ModifyResponse, if non-nil, is called after the backend responds and
+	// before the response is written back to the client. If it returns an
+	// error, ErrorHandler is invoked instead of forwarding the response.
+	ModifyResponse func(resp *Response) error
+
+	// ErrorHandler, if non-nil, is called whenever the proxy fails to reach
+	// the backend or ModifyResponse returns an error. The default handler
+	// responds with StatusBadGateway.
+	ErrorHandler func(ctx *RequestCtx, err error)
+
+	// Client is used to issue the proxied request. If nil, a Client with
+	// default settings is used.
+	Client *Client
+
+	// Logger is used by the default error handling to log errors
+	// encountered while proxying; nothing is logged when ErrorHandler is
+	// set. If nil, the standard library's log package is used.
+	Logger Logger
+}
+
+// NewSingleHostReverseProxy returns a ReverseProxy that routes every request
+// to target, rewriting the scheme/host and joining the request path onto
+// target's path the same way net/http/httputil.NewSingleHostReverseProxy
+// does.
+func NewSingleHostReverseProxy(target *URI) *ReverseProxy {
+	targetScheme := string(target.Scheme())
+	targetHost := string(target.Host())
+	targetPath := string(target.Path())
+	targetQuery := string(target.QueryString())
+
+	return &ReverseProxy{
+		Director: func(req *Request) {
+			u := req.URI()
+			u.SetScheme(targetScheme)
+			u.SetHost(targetHost)
+			u.SetPath(joinURLPath(targetPath, string(u.Path())))
+			if targetQuery == "" || len(u.QueryString()) == 0 {
+				u.SetQueryString(targetQuery + string(u.QueryString()))
+			} else {
+				u.SetQueryString(targetQuery + "&" + string(u.QueryString()))
+			}
+		},
+	}
+}
+
+// joinURLPath joins the backend's base path a with the incoming request
+// path b using exactly one separating slash, mirroring
+// net/http/httputil's singleJoiningSlash.
+func joinURLPath(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
+
+func (p *ReverseProxy) client() *Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return defaultReverseProxyClient
+}
+
+var defaultReverseProxyClient = &Client{}
+
+// Handler is a RequestHandler; p.Handler can be registered with Serve.
+//
+// Handler proxies ctx.Request and ctx.Response themselves, not copies, to
+// and from the backend: whatever buffering or streaming behavior
+// Client.Do and fasthttp's own request/response bodies already have is
+// inherited as-is, rather than this handler imposing an extra copy of its
+// own.
+func (p *ReverseProxy) Handler(ctx *RequestCtx) {
+	req := &ctx.Request
+	resp := &ctx.Response
+
+	stripHopHeaders(&req.Header)
+	p.setForwardedHeaders(ctx, req)
+	p.Director(req)
+
+	if err := p.client().Do(req, resp); err != nil {
+		p.handleError(ctx, err)
+		return
+	}
+
+	stripHopHeaders(&resp.Header)
+
+	if p.ModifyResponse != nil {
+		if err := p.ModifyResponse(resp); err != nil {
+			p.handleError(ctx, err)
+			return
+		}
+	}
+}
+
+// setForwardedHeaders appends the client's address to X-Forwarded-For (as a
+// proxy chain, per convention) and records the scheme/host the client
+// originally connected with in X-Forwarded-Proto/X-Forwarded-Host, so the
+// backend can reconstruct the original request even after Director rewrites
+// req's own URI to point at itself.
+func (p *ReverseProxy) setForwardedHeaders(ctx *RequestCtx, req *Request) {
+	if clientIP, _, err := net.SplitHostPort(ctx.RemoteAddr().String()); err == nil {
+		if prior := req.Header.PeekBytes(strXForwardedFor); len(prior) > 0 {
+			clientIP = string(prior) + ", " + clientIP
+		}
+		req.Header.SetBytesK(strXForwardedFor, clientIP)
+	}
+	req.Header.SetBytesKV(strXForwardedProto, ctx.URI().Scheme())
+	req.Header.SetBytesKV(strXForwardedHost, ctx.Host())
+}
+
+func (p *ReverseProxy) handleError(ctx *RequestCtx, err error) {
+	if p.ErrorHandler != nil {
+		p.ErrorHandler(ctx, err)
+		return
+	}
+	ctx.Error("", StatusBadGateway)
+	p.logf("fasthttp: reverseproxy: error proxying %q: %s", ctx.URI().FullURI(), err)
+}
+
+func (p *ReverseProxy) logf(format string, args ...interface{}) {
+	if p.Logger != nil {
+		p.Logger.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// headerDeleter is implemented by both RequestHeader and ResponseHeader.
+type headerDeleter interface {
+	DelBytes(key []byte)
+}
+
+func stripHopHeaders(h headerDeleter) {
+	for _, k := range hopHeaders {
+		h.DelBytes(k)
+	}
+}
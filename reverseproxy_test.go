@@ -0,0 +1,47 @@
+package fasthttp
+
+import "testing"
+
+func TestJoinURLPath(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected string
+	}{
+		{"/foo", "/bar", "/foo/bar"},
+		{"/foo/", "/bar", "/foo/bar"},
+		{"/foo", "bar", "/foo/bar"},
+		{"/foo/", "bar", "/foo/bar"},
+		{"", "/bar", "/bar"},
+		{"/foo", "", "/foo/"},
+		{"/", "/", "/"},
+	}
+	for _, tc := range testCases {
+		if got := joinURLPath(tc.a, tc.b); got != tc.expected {
+			t.Fatalf("joinURLPath(%q, %q) = %q. Expecting %q", tc.a, tc.b, got, tc.expected)
+		}
+	}
+}
+
+// fakeHeaderDeleter records every key DelBytes is called with, so
+// stripHopHeaders can be tested without a real RequestHeader/ResponseHeader.
+type fakeHeaderDeleter struct {
+	deleted [][]byte
+}
+
+func (h *fakeHeaderDeleter) DelBytes(key []byte) {
+	h.deleted = append(h.deleted, append([]byte{}, key...))
+}
+
+func TestStripHopHeaders(t *testing.T) {
+	h := &fakeHeaderDeleter{}
+	stripHopHeaders(h)
+
+	if len(h.deleted) != len(hopHeaders) {
+		t.Fatalf("unexpected number of deleted headers: %d. Expecting %d", len(h.deleted), len(hopHeaders))
+	}
+	for i, want := range hopHeaders {
+		if string(h.deleted[i]) != string(want) {
+			t.Fatalf("unexpected header deleted at position %d: %q. Expecting %q", i, h.deleted[i], want)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Package router implements a trie-based HTTP request router for fasthttp,
+// in the spirit of gorilla/mux: routes are registered per HTTP method,
+// support named (":id") and catch-all ("*rest") path parameters, and
+// matched parameters are exposed through RequestCtx.UserValue.
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/vickysongang/fasthttp"
+)
+
+// Middleware wraps a fasthttp.RequestHandler with extra behavior, e.g.
+// logging or auth checks, and returns the wrapped handler.
+type Middleware func(fasthttp.RequestHandler) fasthttp.RequestHandler
+
+// Router routes incoming requests to a registered fasthttp.RequestHandler
+// based on HTTP method and path.
+//
+// A Router must not be modified (via Handle/Use) after it starts serving
+// requests.
+type Router struct {
+	trees map[string]*node
+
+	// NotFound is called when no route matches the request path for any
+	// method. Defaults to a plain 404 response.
+	NotFound fasthttp.RequestHandler
+
+	// MethodNotAllowed is called when the request path matches a route
+	// registered under a different method. Defaults to a plain 405
+	// response.
+	MethodNotAllowed fasthttp.RequestHandler
+
+	middleware []Middleware
+}
+
+// New returns an initialized Router.
+func New() *Router {
+	return &Router{
+		trees: make(map[string]*node),
+	}
+}
+
+// Use appends global middleware, applied in registration order to every
+// route registered after this call, innermost route-specific middleware
+// running closest to the handler. Routes registered before the call are
+// not wrapped.
+func (r *Router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// GET registers handler for GET requests to path.
+func (r *Router) GET(path string, handler fasthttp.RequestHandler, mw ...Middleware) {
+	r.Handle(fasthttp.MethodGet, path, handler, mw...)
+}
+
+// HEAD registers handler for HEAD requests to path.
+func (r *Router) HEAD(path string, handler fasthttp.RequestHandler, mw ...Middleware) {
+	r.Handle(fasthttp.MethodHead, path, handler, mw...)
+}
+
+// POST registers handler for POST requests to path.
+func (r *Router) POST(path string, handler fasthttp.RequestHandler, mw ...Middleware) {
+	r.Handle(fasthttp.MethodPost, path, handler, mw...)
+}
+
+// PUT registers handler for PUT requests to path.
+func (r *Router) PUT(path string, handler fasthttp.RequestHandler, mw ...Middleware) {
+	r.Handle(fasthttp.MethodPut, path, handler, mw...)
+}
+
+// PATCH registers handler for PATCH requests to path.
+func (r *Router) PATCH(path string, handler fasthttp.RequestHandler, mw ...Middleware) {
+	r.Handle(fasthttp.MethodPatch, path, handler, mw...)
+}
+
+// DELETE registers handler for DELETE requests to path.
+func (r *Router) DELETE(path string, handler fasthttp.RequestHandler, mw ...Middleware) {
+	r.Handle(fasthttp.MethodDelete, path, handler, mw...)
+}
+
+// Handle registers handler for method requests to path. path segments
+// prefixed with ':' bind a named parameter (e.g. "/users/:id"); a segment
+// prefixed with '*' binds the remainder of the path, including slashes,
+// and must be the last segment (e.g. "/files/*rest").
+func (r *Router) Handle(method, path string, handler fasthttp.RequestHandler, mw ...Middleware) {
+	if len(path) == 0 || path[0] != '/' {
+		panic("router: path must start with '/'")
+	}
+
+	for _, m := range mw {
+		handler = m(handler)
+	}
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+
+	root := r.trees[method]
+	if root == nil {
+		root = &node{}
+		r.trees[method] = root
+	}
+	root.addRoute(splitPath(path), handler)
+}
+
+// ServeFiles registers a GET route, under path (which must end in
+// "/*filepath"), that serves static files out of root via
+// fasthttp.FSHandler, analogous to httprouter.Router.ServeFiles.
+func (r *Router) ServeFiles(path, root string) {
+	const suffix = "/*filepath"
+	if len(path) < len(suffix) || path[len(path)-len(suffix):] != suffix {
+		panic("router: path must end with '" + suffix + "' in ServeFiles, got " + path)
+	}
+	stripSlashes := strings.Count(path[:len(path)-len(suffix)], "/")
+	r.GET(path, fasthttp.FSHandler(root, stripSlashes))
+}
+
+// Handler implements fasthttp.RequestHandler, dispatching ctx to the
+// registered route, r's NotFound handler, or its MethodNotAllowed
+// handler.
+//
+// Handler walks the path byte slice returned by ctx.Path() directly: it
+// never splits the path into a []string or re-decodes a segment, so a
+// request matching a purely static route allocates nothing beyond what
+// fasthttp itself already allocated for the request.
+func (r *Router) Handler(ctx *fasthttp.RequestCtx) {
+	path := ctx.Path()
+	method := ctx.Method()
+
+	if root := r.trees[string(method)]; root != nil {
+		if handler, ok := root.getValue(path, ctx); ok {
+			handler(ctx)
+			return
+		}
+	}
+
+	for m, root := range r.trees {
+		if m == string(method) {
+			continue
+		}
+		if _, ok := root.getValue(path, ctx); ok {
+			r.methodNotAllowed(ctx)
+			return
+		}
+	}
+
+	r.notFound(ctx)
+}
+
+func (r *Router) notFound(ctx *fasthttp.RequestCtx) {
+	if r.NotFound != nil {
+		r.NotFound(ctx)
+		return
+	}
+	ctx.Error("404 page not found", fasthttp.StatusNotFound)
+}
+
+func (r *Router) methodNotAllowed(ctx *fasthttp.RequestCtx) {
+	if r.MethodNotAllowed != nil {
+		r.MethodNotAllowed(ctx)
+		return
+	}
+	ctx.Error("405 method not allowed", fasthttp.StatusMethodNotAllowed)
+}
+
+// node is a single segment of the routing trie. Each node has at most one
+// param child and one wildcard child, since two routes cannot disagree on
+// the parameter name at the same position.
+type node struct {
+	staticChildren map[string]*node
+
+	paramChild *node
+	paramName  string
+
+	wildcardChild *node
+	wildcardName  string
+
+	handler fasthttp.RequestHandler
+}
+
+func (n *node) addRoute(segments []string, handler fasthttp.RequestHandler) {
+	cur := n
+	for _, seg := range segments {
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			name := seg[1:]
+			if cur.paramChild == nil {
+				cur.paramChild = &node{}
+				cur.paramName = name
+			} else if cur.paramName != name {
+				panic(fmt.Sprintf("router: conflicting parameter name %q for %q already registered at this path position", name, cur.paramName))
+			}
+			cur = cur.paramChild
+
+		case strings.HasPrefix(seg, "*"):
+			name := seg[1:]
+			if cur.wildcardChild == nil {
+				cur.wildcardChild = &node{}
+				cur.wildcardName = name
+			} else if cur.wildcardName != name {
+				panic(fmt.Sprintf("router: conflicting wildcard name %q for %q already registered at this path position", name, cur.wildcardName))
+			}
+			cur = cur.wildcardChild
+			// a wildcard consumes the rest of the path, so it must be the
+			// last registered segment.
+
+		default:
+			if cur.staticChildren == nil {
+				cur.staticChildren = make(map[string]*node)
+			}
+			child := cur.staticChildren[seg]
+			if child == nil {
+				child = &node{}
+				cur.staticChildren[seg] = child
+			}
+			cur = child
+		}
+	}
+	cur.handler = handler
+}
+
+// param is one named/wildcard path parameter bound while matching a path.
+type param struct {
+	key   string
+	value string
+}
+
+// getValue walks path against the trie rooted at n and, on a match, binds
+// every parameter collected along the way via ctx.SetUserValue. Parameters
+// are only committed to ctx once the full path is confirmed to resolve to
+// a handler, so a partial match (e.g. "/users/42/bogus" against a
+// registered "/users/:id") never leaves a stale UserValue on ctx for
+// whatever NotFound/other-method handler runs next.
+func (n *node) getValue(path []byte, ctx *fasthttp.RequestCtx) (fasthttp.RequestHandler, bool) {
+	handler, params, ok := n.match(bytes.Trim(path, "/"))
+	if !ok {
+		return nil, false
+	}
+	for _, p := range params {
+		ctx.SetUserValue(p.key, p.value)
+	}
+	return handler, true
+}
+
+func (n *node) match(path []byte) (fasthttp.RequestHandler, []param, bool) {
+	if len(path) == 0 {
+		if n.handler == nil {
+			return nil, nil, false
+		}
+		return n.handler, nil, true
+	}
+
+	seg, rest := path, []byte(nil)
+	if i := bytes.IndexByte(path, '/'); i >= 0 {
+		seg, rest = path[:i], path[i+1:]
+	}
+
+	if child := n.staticChildren[string(seg)]; child != nil {
+		if handler, params, ok := child.match(rest); ok {
+			return handler, params, true
+		}
+	}
+	if n.paramChild != nil {
+		if handler, params, ok := n.paramChild.match(rest); ok {
+			return handler, append(params, param{n.paramName, string(seg)}), true
+		}
+	}
+	if n.wildcardChild != nil && n.wildcardChild.handler != nil {
+		return n.wildcardChild.handler, []param{{n.wildcardName, string(path)}}, true
+	}
+	return nil, nil, false
+}
+
+// splitPath splits an already-normalized path into its non-empty segments.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
@@ -0,0 +1,132 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/vickysongang/fasthttp"
+)
+
+func testCtx(method, requestURI string) *fasthttp.RequestCtx {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(requestURI)
+	return &ctx
+}
+
+func TestRouterUserValueNamedAndWildcard(t *testing.T) {
+	r := New()
+	r.GET("/users/:id/posts/*rest", func(ctx *fasthttp.RequestCtx) {})
+
+	ctx := testCtx(fasthttp.MethodGet, "/users/42/posts/99/comments")
+	r.Handler(ctx)
+
+	if id := ctx.UserValue("id"); id != "42" {
+		t.Fatalf("unexpected id %v. Expecting %q", id, "42")
+	}
+	if rest := ctx.UserValue("rest"); rest != "99/comments" {
+		t.Fatalf("unexpected rest %v. Expecting %q", rest, "99/comments")
+	}
+}
+
+func TestRouterUserValueNotLeakedOnPartialMatch(t *testing.T) {
+	r := New()
+	r.GET("/users/:id", func(ctx *fasthttp.RequestCtx) {})
+
+	ctx := testCtx(fasthttp.MethodGet, "/users/42/bogus")
+	r.Handler(ctx)
+
+	if ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("unexpected status code %d. Expecting %d", ctx.Response.StatusCode(), fasthttp.StatusNotFound)
+	}
+	if id := ctx.UserValue("id"); id != nil {
+		t.Fatalf("expected no UserValue(\"id\") to be set on a partial match, got %v", id)
+	}
+}
+
+func TestRouterNotFound(t *testing.T) {
+	r := New()
+	r.GET("/users/:id", func(ctx *fasthttp.RequestCtx) {})
+
+	ctx := testCtx(fasthttp.MethodGet, "/nope")
+	r.Handler(ctx)
+
+	if ctx.Response.StatusCode() != fasthttp.StatusNotFound {
+		t.Fatalf("unexpected status code %d. Expecting %d", ctx.Response.StatusCode(), fasthttp.StatusNotFound)
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	r := New()
+	r.POST("/users/:id", func(ctx *fasthttp.RequestCtx) {})
+
+	ctx := testCtx(fasthttp.MethodGet, "/users/42")
+	r.Handler(ctx)
+
+	if ctx.Response.StatusCode() != fasthttp.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status code %d. Expecting %d", ctx.Response.StatusCode(), fasthttp.StatusMethodNotAllowed)
+	}
+}
+
+func TestRouterConflictingParamNamePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected a panic for conflicting parameter names")
+		}
+	}()
+
+	r := New()
+	r.GET("/users/:id", func(ctx *fasthttp.RequestCtx) {})
+	r.GET("/users/:name", func(ctx *fasthttp.RequestCtx) {})
+}
+
+func TestRouterServeFilesPanicsOnBadPath(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected a panic for a path not ending in /*filepath")
+		}
+	}()
+
+	r := New()
+	r.ServeFiles("/static", "./testdata")
+}
+
+// switchHandler dispatches the same single route as the benchmarked Router
+// through a hand-written switch, as a baseline for BenchmarkRouterHandler.
+func switchHandler(ctx *fasthttp.RequestCtx) {
+	switch string(ctx.Path()) {
+	case "/users/42/posts/99":
+		ctx.SetUserValue("id", "42")
+		ctx.SetUserValue("rest", "99")
+	default:
+		ctx.Error("404 page not found", fasthttp.StatusNotFound)
+	}
+}
+
+func benchmarkCtx() *fasthttp.RequestCtx {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+	ctx.Request.SetRequestURI("/users/42/posts/99")
+	return &ctx
+}
+
+func BenchmarkRouterHandler(b *testing.B) {
+	r := New()
+	r.GET("/users/:id/posts/*rest", func(ctx *fasthttp.RequestCtx) {})
+	ctx := benchmarkCtx()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Handler(ctx)
+	}
+}
+
+func BenchmarkSwitchHandler(b *testing.B) {
+	ctx := benchmarkCtx()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		switchHandler(ctx)
+	}
+}
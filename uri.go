@@ -0,0 +1,427 @@
+package fasthttp
+
+import (
+	"bytes"
+	"encoding/base64"
+)
+
+// URI represents URI :) .
+//
+// It is forbidden copying URI instances. Create new instance and Parse
+// into it instead.
+//
+// URI instance MUST NOT be used from concurrently running goroutines.
+type URI struct {
+	pathOriginal []byte
+	scheme       []byte
+	path         []byte
+	queryString  []byte
+	hash         []byte
+	host         []byte
+
+	username []byte
+	password []byte
+
+	queryArgs       Args
+	parsedQueryArgs bool
+
+	fullURI []byte
+}
+
+// Scheme returns URI scheme, i.e. http, https, ftp, etc.
+//
+// Returned value is valid until the next URI method call.
+func (u *URI) Scheme() []byte {
+	if len(u.scheme) == 0 {
+		return strHTTP
+	}
+	return u.scheme
+}
+
+// SetScheme sets URI scheme, i.e. http, https, ftp, etc.
+func (u *URI) SetScheme(scheme string) {
+	u.scheme = append(u.scheme[:0], scheme...)
+	lowercaseBytes(u.scheme)
+}
+
+// Host returns host part, i.e. example.com, of the URI, excluding any
+// userinfo that may have been present before the '@'.
+//
+// Returned value is valid until the next URI method call.
+func (u *URI) Host() []byte {
+	return u.host
+}
+
+// SetHost sets host for the URI.
+func (u *URI) SetHost(host string) {
+	u.SetHostBytes([]byte(host))
+}
+
+// SetHostBytes sets host for the URI.
+func (u *URI) SetHostBytes(host []byte) {
+	u.host = append(u.host[:0], host...)
+	lowercaseBytes(u.host)
+}
+
+// Username returns username from the userinfo, if any was present in the
+// parsed URI, percent-decoded.
+//
+// Returned value is valid until the next URI method call.
+func (u *URI) Username() []byte {
+	return u.username
+}
+
+// SetUsername sets username for the URI userinfo.
+func (u *URI) SetUsername(username string) {
+	u.username = append(u.username[:0], username...)
+}
+
+// SetUsernameBytes sets username for the URI userinfo.
+func (u *URI) SetUsernameBytes(username []byte) {
+	u.username = append(u.username[:0], username...)
+}
+
+// Password returns password from the userinfo, if any was present in the
+// parsed URI, percent-decoded.
+//
+// Returned value is valid until the next URI method call.
+func (u *URI) Password() []byte {
+	return u.password
+}
+
+// SetPassword sets password for the URI userinfo.
+func (u *URI) SetPassword(password string) {
+	u.password = append(u.password[:0], password...)
+}
+
+// SetPasswordBytes sets password for the URI userinfo.
+func (u *URI) SetPasswordBytes(password []byte) {
+	u.password = append(u.password[:0], password...)
+}
+
+// BasicAuth returns the "Authorization: Basic ..." header value derived
+// from the Username/Password embedded in this URI's userinfo, and
+// ok=false if no Username was set.
+//
+// This is meant to let a Client populate the Authorization header from a
+// request URI's userinfo when the caller hasn't already set one
+// explicitly, the same way net/http.Client derives Basic auth from a
+// URL's userinfo - but nothing in this package calls BasicAuth yet, so
+// that behavior is not wired in.
+func (u *URI) BasicAuth() (value string, ok bool) {
+	if len(u.username) == 0 {
+		return "", false
+	}
+	auth := append(append([]byte{}, u.username...), ':')
+	auth = append(auth, u.password...)
+	return "Basic " + base64.StdEncoding.EncodeToString(auth), true
+}
+
+// PathOriginal returns the original path passed to Parse (or SetPath),
+// i.e. with no normalization applied.
+//
+// Returned value is valid until the next URI method call.
+func (u *URI) PathOriginal() []byte {
+	return u.pathOriginal
+}
+
+// Path returns normalized path, i.e. with dotdots and duplicate slashes
+// removed and %XX sequences decoded.
+//
+// Returned value is valid until the next URI method call.
+func (u *URI) Path() []byte {
+	if len(u.path) == 0 {
+		return strSlash
+	}
+	return u.path
+}
+
+// SetPath sets URI path.
+func (u *URI) SetPath(path string) {
+	u.SetPathBytes([]byte(path))
+}
+
+// SetPathBytes sets URI path.
+func (u *URI) SetPathBytes(path []byte) {
+	u.pathOriginal = append(u.pathOriginal[:0], path...)
+	u.path = normalizePath(u.path[:0], u.pathOriginal)
+}
+
+// QueryString returns URI query string, i.e. raw, un-decoded part after
+// '?'.
+//
+// Returned value is valid until the next URI method call.
+func (u *URI) QueryString() []byte {
+	return u.queryString
+}
+
+// SetQueryString sets URI query string.
+func (u *URI) SetQueryString(queryString string) {
+	u.queryString = append(u.queryString[:0], queryString...)
+	u.parsedQueryArgs = false
+}
+
+// Hash returns URI hash, i.e. the anchor after '#'.
+//
+// Returned value is valid until the next URI method call.
+func (u *URI) Hash() []byte {
+	return u.hash
+}
+
+// SetHash sets URI hash.
+func (u *URI) SetHash(hash string) {
+	u.hash = append(u.hash[:0], hash...)
+}
+
+// QueryArgs returns query args.
+//
+// The returned args are valid until the next URI method call.
+func (u *URI) QueryArgs() *Args {
+	if !u.parsedQueryArgs {
+		u.queryArgs.ParseBytes(u.queryString)
+		u.parsedQueryArgs = true
+	}
+	return &u.queryArgs
+}
+
+// Parse initializes URI from the given host and uri.
+//
+// If uri is an absolute URI (i.e. starts with a scheme followed by "://"),
+// the scheme and host embedded in uri take priority over host. Otherwise
+// host is used as-is and uri is treated as the request path/query/hash.
+func (u *URI) Parse(host, uri []byte) {
+	u.parse(host, uri)
+}
+
+func (u *URI) parse(host, uri []byte) {
+	scheme, host, requestURI := splitHostURI(host, uri)
+
+	u.scheme = append(u.scheme[:0], scheme...)
+	lowercaseBytes(u.scheme)
+
+	u.setHost(host)
+
+	b := requestURI
+	n := bytes.IndexByte(b, '#')
+	if n >= 0 {
+		u.hash = append(u.hash[:0], b[n+1:]...)
+		b = b[:n]
+	} else {
+		u.hash = u.hash[:0]
+	}
+
+	n = bytes.IndexByte(b, '?')
+	if n >= 0 {
+		u.queryString = append(u.queryString[:0], b[n+1:]...)
+		b = b[:n]
+	} else {
+		u.queryString = u.queryString[:0]
+	}
+	u.parsedQueryArgs = false
+
+	u.pathOriginal = append(u.pathOriginal[:0], b...)
+	u.path = normalizePath(u.path[:0], u.pathOriginal)
+}
+
+// setHost splits userinfo off host, percent-decoding it, before storing
+// the remaining (lowercased) host.
+func (u *URI) setHost(host []byte) {
+	u.username = u.username[:0]
+	u.password = u.password[:0]
+
+	if n := bytes.LastIndexByte(host, '@'); n >= 0 {
+		userinfo := host[:n]
+		host = host[n+1:]
+		if m := bytes.IndexByte(userinfo, ':'); m >= 0 {
+			u.username = decodePercent(u.username[:0], userinfo[:m])
+			u.password = decodePercent(u.password[:0], userinfo[m+1:])
+		} else {
+			u.username = decodePercent(u.username[:0], userinfo)
+		}
+	}
+
+	u.host = append(u.host[:0], host...)
+	lowercaseBytes(u.host)
+}
+
+// splitHostURI splits uri into a scheme, host and the remaining request
+// URI. If uri doesn't embed an absolute "scheme://host" prefix, host is
+// returned unchanged and the whole of uri is the request URI.
+func splitHostURI(host, uri []byte) (scheme, newHost, requestURI []byte) {
+	n := bytes.Index(uri, strColonSlashSlash)
+	if n < 0 || bytes.IndexByte(uri[:n], '/') >= 0 {
+		return strHTTP, host, uri
+	}
+	scheme = uri[:n]
+	if len(scheme) == 0 {
+		scheme = strHTTP
+	}
+	uri = uri[n+len(strColonSlashSlash):]
+	n = bytes.IndexByte(uri, '/')
+	if n < 0 {
+		return scheme, uri, strSlash
+	}
+	return scheme, uri[:n], uri[n:]
+}
+
+// FullURI returns full URI, i.e. scheme://[user[:password]@]host/path?query#hash.
+//
+// Returned value is valid until the next URI method call.
+func (u *URI) FullURI() []byte {
+	u.fullURI = u.AppendBytes(u.fullURI[:0])
+	return u.fullURI
+}
+
+// AppendBytes appends full URI to dst and returns the resulting dst.
+func (u *URI) AppendBytes(dst []byte) []byte {
+	dst = append(dst, u.Scheme()...)
+	dst = append(dst, strColonSlashSlash...)
+	if len(u.username) > 0 {
+		dst = appendQuotedUserinfo(dst, u.username)
+		if len(u.password) > 0 {
+			dst = append(dst, ':')
+			dst = appendQuotedUserinfo(dst, u.password)
+		}
+		dst = append(dst, '@')
+	}
+	dst = appendLowercase(dst, u.Host())
+	dst = appendQuotedPath(dst, u.Path())
+
+	if u.parsedQueryArgs && u.queryArgs.Len() > 0 {
+		dst = append(dst, '?')
+		dst = u.queryArgs.AppendBytes(dst)
+	} else if len(u.queryString) > 0 {
+		dst = append(dst, '?')
+		dst = append(dst, u.queryString...)
+	}
+
+	if len(u.hash) > 0 {
+		dst = append(dst, '#')
+		dst = append(dst, u.hash...)
+	}
+	return dst
+}
+
+// normalizePath removes dotdots and duplicate slashes from src, decoding
+// %XX sequences along the way, and appends the result to dst.
+func normalizePath(dst, src []byte) []byte {
+	decoded := decodePercent(nil, src)
+
+	var segments [][]byte
+	lastWasDotDot := false
+	i := 0
+	for i < len(decoded) {
+		for i < len(decoded) && decoded[i] == '/' {
+			i++
+		}
+		start := i
+		for i < len(decoded) && decoded[i] != '/' {
+			i++
+		}
+		if start == i {
+			continue
+		}
+		seg := decoded[start:i]
+		switch {
+		case len(seg) == 1 && seg[0] == '.':
+			lastWasDotDot = false
+		case len(seg) == 2 && seg[0] == '.' && seg[1] == '.':
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+			lastWasDotDot = true
+		default:
+			segments = append(segments, seg)
+			lastWasDotDot = false
+		}
+	}
+
+	if len(segments) == 0 {
+		return append(dst, '/')
+	}
+	for _, seg := range segments {
+		dst = append(dst, '/')
+		dst = append(dst, seg...)
+	}
+	trailingSlash := lastWasDotDot || (len(decoded) > 0 && decoded[len(decoded)-1] == '/')
+	if trailingSlash {
+		dst = append(dst, '/')
+	}
+	return dst
+}
+
+// decodePercent percent-decodes src (without treating '+' specially, since
+// it is used for paths and userinfo, not form-encoded query args) and
+// appends the result to dst.
+func decodePercent(dst, src []byte) []byte {
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if c == '%' && i+2 < len(src) {
+			if h, ok := unhex2(src[i+1], src[i+2]); ok {
+				dst = append(dst, h)
+				i += 2
+				continue
+			}
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// appendQuotedPath percent-encodes everything in src outside of
+// [A-Za-z0-9-_.~/] and appends the result to dst.
+func appendQuotedPath(dst, src []byte) []byte {
+	for _, c := range src {
+		if isPathSafeByte(c) {
+			dst = append(dst, c)
+		} else {
+			dst = append(dst, '%', upperhex[c>>4], upperhex[c&0xf])
+		}
+	}
+	return dst
+}
+
+// appendQuotedUserinfo percent-encodes everything in src outside of
+// [A-Za-z0-9-_.~] and appends the result to dst.
+func appendQuotedUserinfo(dst, src []byte) []byte {
+	for _, c := range src {
+		if isUnreservedByte(c) {
+			dst = append(dst, c)
+		} else {
+			dst = append(dst, '%', upperhex[c>>4], upperhex[c&0xf])
+		}
+	}
+	return dst
+}
+
+func isPathSafeByte(c byte) bool {
+	return isUnreservedByte(c) || c == '/'
+}
+
+func isUnreservedByte(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	case c == '-' || c == '_' || c == '.' || c == '~':
+		return true
+	}
+	return false
+}
+
+func appendLowercase(dst, src []byte) []byte {
+	for _, c := range src {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+func lowercaseBytes(b []byte) {
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+}